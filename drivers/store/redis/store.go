@@ -2,10 +2,11 @@ package redis
 
 import (
 	"context"
+	"crypto/sha1"
 	"fmt"
 	"time"
 
-	libredis "github.com/go-redis/redis"
+	libredis "github.com/go-redis/redis/v8"
 	"github.com/pkg/errors"
 
 	"github.com/ulule/limiter"
@@ -13,16 +14,42 @@ import (
 )
 
 // Client is an interface thats allows to use a redis cluster or a redis single client seamlessly.
+// Every method takes the caller's context so cancellation, deadlines, and tracing propagate down
+// to the underlying redis command.
 type Client interface {
-	Ping() *libredis.StatusCmd
-	Get(key string) *libredis.StringCmd
-	Set(key string, value interface{}, expiration time.Duration) *libredis.StatusCmd
-	Watch(handler func(*libredis.Tx) error, keys ...string) error
-	Del(keys ...string) *libredis.IntCmd
-	SetNX(key string, value interface{}, expiration time.Duration) *libredis.BoolCmd
-	Eval(script string, keys []string, args ...interface{}) *libredis.Cmd
+	Ping(ctx context.Context) *libredis.StatusCmd
+	Get(ctx context.Context, key string) *libredis.StringCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *libredis.StatusCmd
+	Watch(ctx context.Context, handler func(*libredis.Tx) error, keys ...string) error
+	Del(ctx context.Context, keys ...string) *libredis.IntCmd
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *libredis.BoolCmd
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) *libredis.Cmd
+	EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *libredis.Cmd
+	ScriptLoad(ctx context.Context, script string) *libredis.StringCmd
 }
 
+// getValScript atomically increments the counter stored at KEYS[1] by ARGV[1] and makes sure it
+// expires after ARGV[2] milliseconds. It returns the updated counter along with its remaining TTL
+// in milliseconds, so a single round trip replaces the former WATCH/SetNX/IncrBy/PTTL sequence.
+const getValScript = `
+local c = redis.call("INCRBY", KEYS[1], ARGV[1])
+if c == tonumber(ARGV[1]) then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return {c, tonumber(ARGV[2])}
+else
+	local t = redis.call("PTTL", KEYS[1])
+	if t < 0 then
+		redis.call("PEXPIRE", KEYS[1], ARGV[2])
+		t = tonumber(ARGV[2])
+	end
+	return {c, t}
+end
+`
+
+// getValScriptSHA is the SHA1 digest of getValScript, precomputed so EVALSHA can be attempted
+// without an extra round trip to load the script first.
+var getValScriptSHA = fmt.Sprintf("%x", sha1.Sum([]byte(getValScript)))
+
 // Store is the redis store.
 type Store struct {
 	// Prefix used for the key.
@@ -53,7 +80,7 @@ func NewStoreWithOptions(client Client, options limiter.StoreOptions) (limiter.S
 		store.MaxRetry = 1
 	}
 
-	_, err := store.ping()
+	_, err := store.ping(context.Background())
 	if err != nil {
 		return nil, err
 	}
@@ -66,41 +93,62 @@ func (store *Store) GetVal(ctx context.Context, key string, rate limiter.Rate, v
 	key = fmt.Sprintf("%s:%s", store.Prefix, key)
 	now := time.Now()
 
-	lctx := limiter.Context{}
-	onWatch := func(rtx *libredis.Tx) error {
+	count, ttl, err := store.evalGetVal(ctx, key, rate.Period, val)
+	if err != nil {
+		err = errors.Wrapf(err, "limiter: cannot get value for %s", key)
+		return limiter.Context{}, err
+	}
 
-		created, err := store.doSetValue(rtx, key, rate.Period, val)
-		if err != nil {
-			return err
-		}
+	expiration := now.Add(ttl)
+	return common.GetContextFromState(now, rate, expiration, count), nil
+}
 
-		if created {
-			expiration := now.Add(rate.Period)
-			lctx = common.GetContextFromState(now, rate, expiration, val)
-			return nil
-		}
+// evalGetVal atomically increments key by val and ensures it expires after period, returning the
+// updated counter and its remaining TTL. It runs getValScript via EVALSHA, falling back to EVAL
+// (which primes the script cache) on a NOSCRIPT miss.
+func (store *Store) evalGetVal(ctx context.Context, key string, period time.Duration, val int64) (int64, time.Duration, error) {
+	periodMS := int64(period / time.Millisecond)
 
-		count, ttl, err := store.doUpdateValue(rtx, key, rate.Period, val)
-		if err != nil {
-			return err
-		}
+	reply, err := store.client.EvalSha(ctx, getValScriptSHA, []string{key}, val, periodMS).Result()
+	if err != nil && isNoScriptErr(err) {
+		reply, err = store.client.Eval(ctx, getValScript, []string{key}, val, periodMS).Result()
+	}
+	if err != nil {
+		return 0, 0, err
+	}
 
-		expiration := now.Add(rate.Period)
-		if ttl > 0 {
-			expiration = now.Add(ttl)
-		}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return 0, 0, errors.New("limiter: unexpected reply from getValScript")
+	}
 
-		lctx = common.GetContextFromState(now, rate, expiration, count)
-		return nil
+	count, err := toInt64(values[0])
+	if err != nil {
+		return 0, 0, err
 	}
 
-	err := store.client.Watch(onWatch, key)
+	ttlMS, err := toInt64(values[1])
 	if err != nil {
-		err = errors.Wrapf(err, "limiter: cannot get value for %s", key)
-		return limiter.Context{}, err
+		return 0, 0, err
 	}
 
-	return lctx, nil
+	return count, time.Duration(ttlMS) * time.Millisecond, nil
+}
+
+// isNoScriptErr reports whether err is the NOSCRIPT error redis returns when EVALSHA references
+// a script that isn't loaded in the script cache.
+func isNoScriptErr(err error) bool {
+	return err != nil && len(err.Error()) >= 8 && err.Error()[:8] == "NOSCRIPT"
+}
+
+// toInt64 converts a Lua script reply element to an int64.
+func toInt64(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int64:
+		return n, nil
+	default:
+		return 0, errors.Errorf("limiter: cannot convert %T to int64", v)
+	}
 }
 
 // Get returns the limit for given identifier.
@@ -115,7 +163,7 @@ func (store *Store) Peek(ctx context.Context, key string, rate limiter.Rate) (li
 
 	lctx := limiter.Context{}
 	onWatch := func(rtx *libredis.Tx) error {
-		count, ttl, err := store.doPeekValue(rtx, key)
+		count, ttl, err := store.doPeekValue(ctx, rtx, key)
 		if err != nil {
 			return err
 		}
@@ -129,7 +177,7 @@ func (store *Store) Peek(ctx context.Context, key string, rate limiter.Rate) (li
 		return nil
 	}
 
-	err := store.client.Watch(onWatch, key)
+	err := store.client.Watch(ctx, onWatch, key)
 	if err != nil {
 		err = errors.Wrapf(err, "limiter: cannot peek value for %s", key)
 		return limiter.Context{}, err
@@ -139,9 +187,9 @@ func (store *Store) Peek(ctx context.Context, key string, rate limiter.Rate) (li
 }
 
 // doPeekValue will execute peekValue with a retry mecanism (optimistic locking) until store.MaxRetry is reached.
-func (store *Store) doPeekValue(rtx *libredis.Tx, key string) (int64, time.Duration, error) {
+func (store *Store) doPeekValue(ctx context.Context, rtx *libredis.Tx, key string) (int64, time.Duration, error) {
 	for i := 0; i < store.MaxRetry; i++ {
-		count, ttl, err := peekValue(rtx, key)
+		count, ttl, err := peekValue(ctx, rtx, key)
 		if err == nil {
 			return count, ttl, nil
 		}
@@ -150,12 +198,12 @@ func (store *Store) doPeekValue(rtx *libredis.Tx, key string) (int64, time.Durat
 }
 
 // peekValue will retrieve the counter and its expiration for given key.
-func peekValue(rtx *libredis.Tx, key string) (int64, time.Duration, error) {
+func peekValue(ctx context.Context, rtx *libredis.Tx, key string) (int64, time.Duration, error) {
 	pipe := rtx.Pipeline()
-	value := pipe.Get(key)
-	expire := pipe.PTTL(key)
+	value := pipe.Get(ctx, key)
+	expire := pipe.PTTL(ctx, key)
 
-	_, err := pipe.Exec()
+	_, err := pipe.Exec(ctx)
 	if err != nil && err != libredis.Nil {
 		return 0, 0, err
 	}
@@ -173,88 +221,9 @@ func peekValue(rtx *libredis.Tx, key string) (int64, time.Duration, error) {
 	return count, ttl, nil
 }
 
-// doSetValue will execute setValue with a retry mecanism (optimistic locking) until store.MaxRetry is reached.
-func (store *Store) doSetValue(rtx *libredis.Tx, key string, expiration time.Duration, val int64) (bool, error) {
-	for i := 0; i < store.MaxRetry; i++ {
-		created, err := setValue(rtx, key, expiration, val)
-		if err == nil {
-			return created, nil
-		}
-	}
-	return false, errors.New("retry limit exceeded")
-}
-
-// setValue will try to initialize a new counter if given key doesn't exists.
-func setValue(rtx *libredis.Tx, key string, expiration time.Duration, val int64) (bool, error) {
-	value := rtx.SetNX(key, val, expiration)
-
-	created, err := value.Result()
-	if err != nil {
-		return false, err
-	}
-
-	return created, nil
-}
-
-// doUpdateValue will execute setValue with a retry mecanism (optimistic locking) until store.MaxRetry is reached.
-func (store *Store) doUpdateValue(rtx *libredis.Tx, key string,
-	expiration time.Duration, val int64) (int64, time.Duration, error) {
-	for i := 0; i < store.MaxRetry; i++ {
-		count, ttl, err := updateValue(rtx, key, expiration, val)
-		if err == nil {
-			return count, ttl, nil
-		}
-
-		// If ttl is negative and there is an error, do not retry an update.
-		if ttl < 0 {
-			return 0, 0, err
-		}
-	}
-	return 0, 0, errors.New("retry limit exceeded")
-}
-
-// updateValue will try to increment the counter identified by given key.
-func updateValue(rtx *libredis.Tx, key string, expiration time.Duration, val int64) (int64, time.Duration, error) {
-	pipe := rtx.Pipeline()
-	value := pipe.IncrBy(key, val)
-	expire := pipe.PTTL(key)
-
-	_, err := pipe.Exec()
-	if err != nil {
-		return 0, 0, err
-	}
-
-	count, err := value.Result()
-	if err != nil {
-		return 0, 0, err
-	}
-
-	ttl, err := expire.Result()
-	if err != nil {
-		return 0, 0, err
-	}
-
-	// If ttl is negative, we have to define key expiration.
-	if ttl < 0 {
-		expire := rtx.Expire(key, expiration)
-
-		ok, err := expire.Result()
-		if err != nil {
-			return count, ttl, err
-		}
-
-		if !ok {
-			return count, ttl, errors.New("cannot configure timeout on key")
-		}
-	}
-
-	return count, ttl, nil
-
-}
-
 // ping checks if redis is alive.
-func (store *Store) ping() (bool, error) {
-	cmd := store.client.Ping()
+func (store *Store) ping(ctx context.Context) (bool, error) {
+	cmd := store.client.Ping(ctx)
 
 	pong, err := cmd.Result()
 	if err != nil {
@@ -0,0 +1,150 @@
+package redis
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ulule/limiter"
+	"github.com/ulule/limiter/drivers/store/common"
+)
+
+// getBatchScript applies the same INCRBY/PEXPIRE logic as getValScript to every key in KEYS, in a
+// single round trip, and returns one {count, ttl} tuple per key in the same order.
+const getBatchScript = `
+local results = {}
+for i, key in ipairs(KEYS) do
+	local c = redis.call("INCRBY", key, ARGV[1])
+	if c == tonumber(ARGV[1]) then
+		redis.call("PEXPIRE", key, ARGV[2])
+		results[i] = {c, tonumber(ARGV[2])}
+	else
+		local t = redis.call("PTTL", key)
+		if t < 0 then
+			redis.call("PEXPIRE", key, ARGV[2])
+			t = tonumber(ARGV[2])
+		end
+		results[i] = {c, t}
+	end
+end
+return results
+`
+
+var getBatchScriptSHA = fmt.Sprintf("%x", sha1.Sum([]byte(getBatchScript)))
+
+// peekBatchScript reads the counter and TTL for every key in KEYS, without modification, and
+// returns one {count, ttl} tuple per key in the same order.
+const peekBatchScript = `
+local results = {}
+for i, key in ipairs(KEYS) do
+	local c = tonumber(redis.call("GET", key)) or 0
+	local t = redis.call("PTTL", key)
+	results[i] = {c, t}
+end
+return results
+`
+
+var peekBatchScriptSHA = fmt.Sprintf("%x", sha1.Sum([]byte(peekBatchScript)))
+
+// HashTag formats key so that it lands on the same Redis Cluster hash slot as every other key
+// sharing tag. GetBatch and PeekBatch run a single multi-key script, which Redis Cluster only
+// allows when every key in the batch hashes to the same slot -- so callers batching per-user +
+// per-IP + per-route + per-tenant checks for one request should wrap each key with the same tag,
+// e.g. HashTag(tenantID, "user:42"). The tag is part of the stored key, so it must stay the same
+// from one request to the next for counters to accumulate.
+func HashTag(tag, key string) string {
+	return fmt.Sprintf("{%s}:%s", tag, key)
+}
+
+// GetBatch returns the limit for each of the given identifiers in a single round trip. All keys
+// share rate and are incremented by 1, as with Get. On Redis Cluster, every key must share a hash
+// tag (see HashTag) so the batch lands on one slot.
+func (store *Store) GetBatch(ctx context.Context, keys []string, rate limiter.Rate) ([]limiter.Context, error) {
+	now := time.Now()
+	periodMS := int64(rate.Period / time.Millisecond)
+
+	reply, err := store.client.EvalSha(ctx, getBatchScriptSHA, store.prefixKeys(keys), int64(1), periodMS).Result()
+	if err != nil && isNoScriptErr(err) {
+		reply, err = store.client.Eval(ctx, getBatchScript, store.prefixKeys(keys), int64(1), periodMS).Result()
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "limiter: cannot get batch value")
+	}
+
+	return parseBatchReply(now, rate, reply)
+}
+
+// PeekBatch returns the limit for each of the given identifiers in a single round trip, without
+// modification on current values. On Redis Cluster, every key must share a hash tag (see HashTag)
+// so the batch lands on one slot.
+func (store *Store) PeekBatch(ctx context.Context, keys []string, rate limiter.Rate) ([]limiter.Context, error) {
+	now := time.Now()
+
+	reply, err := store.client.EvalSha(ctx, peekBatchScriptSHA, store.prefixKeys(keys)).Result()
+	if err != nil && isNoScriptErr(err) {
+		reply, err = store.client.Eval(ctx, peekBatchScript, store.prefixKeys(keys)).Result()
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "limiter: cannot peek batch value")
+	}
+
+	return parseBatchReply(now, rate, reply)
+}
+
+// prefixKeys applies store.Prefix to every key, the same way Get and Peek do for a single key.
+func (store *Store) prefixKeys(keys []string) []string {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = fmt.Sprintf("%s:%s", store.Prefix, key)
+	}
+	return prefixed
+}
+
+// parseBatchReply decodes the []{count, ttl} reply shared by getBatchScript and peekBatchScript
+// into one limiter.Context per key, in order.
+func parseBatchReply(now time.Time, rate limiter.Rate, reply interface{}) ([]limiter.Context, error) {
+	rows, ok := reply.([]interface{})
+	if !ok {
+		return nil, errors.New("limiter: unexpected reply from batch script")
+	}
+
+	result := make([]limiter.Context, len(rows))
+	for i, row := range rows {
+		count, ttlMS, err := parseCountAndTTL(row)
+		if err != nil {
+			return nil, err
+		}
+
+		expiration := now.Add(rate.Period)
+		if ttlMS > 0 {
+			expiration = now.Add(time.Duration(ttlMS) * time.Millisecond)
+		}
+
+		result[i] = common.GetContextFromState(now, rate, expiration, count)
+	}
+
+	return result, nil
+}
+
+// parseCountAndTTL decodes a single {count, ttl} row from a batch script reply.
+func parseCountAndTTL(row interface{}) (int64, int64, error) {
+	values, ok := row.([]interface{})
+	if !ok || len(values) != 2 {
+		return 0, 0, errors.New("limiter: unexpected row in batch script reply")
+	}
+
+	count, err := toInt64(values[0])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	ttlMS, err := toInt64(values[1])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return count, ttlMS, nil
+}
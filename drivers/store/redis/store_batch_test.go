@@ -0,0 +1,105 @@
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	libredis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter"
+	"github.com/ulule/limiter/drivers/store/redis"
+)
+
+func newBatchTestStore(t *testing.T) (*redis.Store, func()) {
+	t.Helper()
+
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+
+	client := libredis.NewClient(&libredis.Options{Addr: server.Addr()})
+	store, err := redis.NewStore(client)
+	require.NoError(t, err)
+
+	return store.(*redis.Store), server.Close
+}
+
+func TestStoreGetBatchIncrementsEachKeyIndependently(t *testing.T) {
+	store, cleanup := newBatchTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	rate := limiter.Rate{Limit: 5, Period: time.Minute}
+	keys := []string{
+		redis.HashTag("req-1", "user:42"),
+		redis.HashTag("req-1", "ip:1.2.3.4"),
+	}
+
+	contexts, err := store.GetBatch(ctx, keys, rate)
+	require.NoError(t, err)
+	require.Len(t, contexts, len(keys))
+	for i, lctx := range contexts {
+		require.Equal(t, rate.Limit-1, lctx.Remaining, "key %d", i)
+	}
+
+	contexts, err = store.GetBatch(ctx, keys, rate)
+	require.NoError(t, err)
+	for i, lctx := range contexts {
+		require.Equal(t, rate.Limit-2, lctx.Remaining, "key %d after second call", i)
+	}
+}
+
+func TestStorePeekBatchDoesNotConsume(t *testing.T) {
+	store, cleanup := newBatchTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	rate := limiter.Rate{Limit: 5, Period: time.Minute}
+	keys := []string{"alice", "bob"}
+
+	_, err := store.GetBatch(ctx, keys, rate)
+	require.NoError(t, err)
+
+	before, err := store.PeekBatch(ctx, keys, rate)
+	require.NoError(t, err)
+
+	after, err := store.PeekBatch(ctx, keys, rate)
+	require.NoError(t, err)
+
+	for i := range keys {
+		require.Equal(t, before[i].Remaining, after[i].Remaining, "key %d: PeekBatch must not consume", i)
+	}
+}
+
+func TestHashTagSharesLiteralBraceSegment(t *testing.T) {
+	require.Equal(t, "{tenant-9}:user:42", redis.HashTag("tenant-9", "user:42"))
+}
+
+// TestStoreGetBatchRealRedis is the real-redis counterpart to
+// TestStoreGetBatchIncrementsEachKeyIndependently: the batch scripts run a single EVAL across
+// multiple hash-tagged keys, which miniredis's Lua emulation doesn't exercise the same way a real
+// Redis server does.
+func TestStoreGetBatchRealRedis(t *testing.T) {
+	client := requireRedisURI(t)
+	ctx := context.Background()
+	require.NoError(t, client.FlushDB(ctx).Err())
+	defer client.FlushDB(ctx)
+
+	storeIface, err := redis.NewStore(client)
+	require.NoError(t, err)
+	store := storeIface.(*redis.Store)
+
+	rate := limiter.Rate{Limit: 5, Period: time.Minute}
+	keys := []string{
+		redis.HashTag("req-1", "user:42"),
+		redis.HashTag("req-1", "ip:1.2.3.4"),
+	}
+
+	contexts, err := store.GetBatch(ctx, keys, rate)
+	require.NoError(t, err)
+	for i, lctx := range contexts {
+		require.Equal(t, rate.Limit-1, lctx.Remaining, "key %d", i)
+	}
+}
@@ -0,0 +1,244 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ulule/limiter"
+	"github.com/ulule/limiter/drivers/store/memory"
+)
+
+// FailurePolicy controls what a FailoverStore does with requests while redis is considered down.
+type FailurePolicy int
+
+const (
+	// FailClosed rejects requests with an error while redis is unreachable. This mirrors the
+	// behaviour of Store on its own.
+	FailClosed FailurePolicy = iota
+	// FailOpen allows every request while redis is unreachable, synthesizing a limiter.Context
+	// that reports the request as within limit.
+	FailOpen
+	// FailLocal falls back to an in-process counter (drivers/store/memory), keyed by the same
+	// prefix, while redis is unreachable.
+	FailLocal
+)
+
+// FailoverOptions configures the circuit breaker wrapped around a redis store.
+type FailoverOptions struct {
+	// Policy controls what happens to requests while redis is unreachable.
+	Policy FailurePolicy
+	// FailureThreshold is the number of consecutive errors that trip the breaker open. Defaults
+	// to 5.
+	FailureThreshold int
+	// CooldownPeriod is how long the breaker stays open before probing redis again. Defaults to
+	// 30 seconds.
+	CooldownPeriod time.Duration
+	// OnRedisDown, if set, is called the moment the breaker trips open.
+	OnRedisDown func(err error)
+	// OnRedisRecovered, if set, is called the moment a probe succeeds and the breaker closes.
+	OnRedisRecovered func()
+}
+
+// FailoverStore wraps a redis Store with a half-open circuit breaker: after FailureThreshold
+// consecutive errors it stops hitting redis for CooldownPeriod and serves Policy instead, then
+// probes redis with a PING before resuming normal operation. This keeps rate-limited endpoints
+// available during a redis outage instead of failing every request.
+type FailoverStore struct {
+	client   Client
+	primary  *Store
+	fallback limiter.Store
+	options  FailoverOptions
+	breaker  *circuitBreaker
+}
+
+// NewFailoverStore returns a redis store wrapped with a circuit breaker implementing options.Policy.
+func NewFailoverStore(client Client, storeOptions limiter.StoreOptions, options FailoverOptions) (limiter.Store, error) {
+	if options.FailureThreshold <= 0 {
+		options.FailureThreshold = 5
+	}
+	if options.CooldownPeriod <= 0 {
+		options.CooldownPeriod = 30 * time.Second
+	}
+
+	primary := &Store{
+		client:   client,
+		Prefix:   storeOptions.Prefix,
+		MaxRetry: storeOptions.MaxRetry,
+	}
+	if primary.MaxRetry <= 0 {
+		primary.MaxRetry = 1
+	}
+
+	var fallback limiter.Store
+	if options.Policy == FailLocal {
+		fallback = memory.NewStoreWithOptions(storeOptions)
+	}
+
+	return &FailoverStore{
+		client:   client,
+		primary:  primary,
+		fallback: fallback,
+		options:  options,
+		breaker:  newCircuitBreaker(options.FailureThreshold, options.CooldownPeriod),
+	}, nil
+}
+
+// Get returns the limit for given identifier, serving options.Policy while redis is unreachable.
+func (store *FailoverStore) Get(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	return store.do(ctx, key, rate,
+		func() (limiter.Context, error) { return store.primary.Get(ctx, key, rate) },
+		func() (limiter.Context, error) { return store.fallback.Get(ctx, key, rate) },
+	)
+}
+
+// Peek returns the limit for given identifier, without modification, serving options.Policy while
+// redis is unreachable.
+func (store *FailoverStore) Peek(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	return store.do(ctx, key, rate,
+		func() (limiter.Context, error) { return store.primary.Peek(ctx, key, rate) },
+		func() (limiter.Context, error) { return store.fallback.Peek(ctx, key, rate) },
+	)
+}
+
+// do runs call against redis unless the breaker is open, in which case it serves
+// store.options.Policy until the cooldown has elapsed and then probes redis first. It also falls
+// back to store.options.Policy if the probe fails or the call itself errors.
+func (store *FailoverStore) do(ctx context.Context, key string, rate limiter.Rate,
+	call func() (limiter.Context, error), fallbackCall func() (limiter.Context, error)) (limiter.Context, error) {
+
+	if store.breaker.isOpen() {
+		if !store.breaker.readyToProbe() {
+			return store.serveFallback(rate, fallbackCall)
+		}
+
+		if !store.probe(ctx) {
+			store.breaker.recordProbeFailure()
+			return store.serveFallback(rate, fallbackCall)
+		}
+
+		store.breaker.close()
+		if store.options.OnRedisRecovered != nil {
+			store.options.OnRedisRecovered()
+		}
+	}
+
+	lctx, err := call()
+	if err != nil {
+		if store.breaker.recordFailure() && store.options.OnRedisDown != nil {
+			store.options.OnRedisDown(err)
+		}
+		return store.serveFallback(rate, fallbackCall)
+	}
+
+	store.breaker.recordSuccess()
+	return lctx, nil
+}
+
+// serveFallback answers a request that couldn't reach redis, according to store.options.Policy.
+func (store *FailoverStore) serveFallback(rate limiter.Rate,
+	fallbackCall func() (limiter.Context, error)) (limiter.Context, error) {
+
+	switch store.options.Policy {
+	case FailOpen:
+		return unlimitedContext(rate), nil
+	case FailLocal:
+		return fallbackCall()
+	default:
+		return limiter.Context{}, errors.New("limiter: redis is unreachable")
+	}
+}
+
+// probe checks whether redis has come back up.
+func (store *FailoverStore) probe(ctx context.Context) bool {
+	_, err := store.client.Ping(ctx).Result()
+	return err == nil
+}
+
+// unlimitedContext synthesizes a limiter.Context reporting a request as within limit, for
+// FailOpen.
+func unlimitedContext(rate limiter.Rate) limiter.Context {
+	return limiter.Context{
+		Limit:     rate.Limit,
+		Remaining: rate.Limit,
+		Reset:     time.Now().Add(rate.Period).Unix(),
+		Reached:   false,
+	}
+}
+
+// circuitBreaker is a half-open circuit breaker: it opens after failureThreshold consecutive
+// failures, stays open for cooldown, then allows a single probe through before fully closing
+// again.
+type circuitBreaker struct {
+	mu               sync.Mutex
+	failureThreshold int
+	cooldown         time.Duration
+	failures         int
+	open             bool
+	openedAt         time.Time
+}
+
+// newCircuitBreaker returns a closed circuitBreaker.
+func newCircuitBreaker(failureThreshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+	}
+}
+
+// isOpen reports whether the breaker is currently open.
+func (b *circuitBreaker) isOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.open
+}
+
+// readyToProbe reports whether cooldown has elapsed since the breaker opened.
+func (b *circuitBreaker) readyToProbe() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Since(b.openedAt) >= b.cooldown
+}
+
+// recordProbeFailure resets the cooldown window after an attempted recovery probe fails. Unlike
+// readyToProbe's no-op wait path, this only fires once per cooldown, so steady traffic arriving
+// faster than cooldown can't keep the timer perpetually restarted and the breaker permanently
+// open.
+func (b *circuitBreaker) recordProbeFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.openedAt = time.Now()
+}
+
+// recordFailure registers a failed call and reports whether it just tripped the breaker open.
+func (b *circuitBreaker) recordFailure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.open {
+		return false
+	}
+	b.failures++
+	if b.failures < b.failureThreshold {
+		return false
+	}
+	b.open = true
+	b.openedAt = time.Now()
+	return true
+}
+
+// recordSuccess clears the failure count after a successful call.
+func (b *circuitBreaker) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+}
+
+// close resets the breaker to its initial, closed state.
+func (b *circuitBreaker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.open = false
+	b.failures = 0
+}
@@ -0,0 +1,162 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	libredis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter"
+	"github.com/ulule/limiter/drivers/store/redis"
+)
+
+// fakeClient is a minimal redis.Client double whose outage can be toggled on demand, so the
+// circuit breaker's timing logic can be tested without a real redis server.
+type fakeClient struct {
+	down      int32
+	evalCalls int32
+}
+
+func (f *fakeClient) setDown(v bool) {
+	val := int32(0)
+	if v {
+		val = 1
+	}
+	atomic.StoreInt32(&f.down, val)
+}
+
+func (f *fakeClient) isDown() bool {
+	return atomic.LoadInt32(&f.down) == 1
+}
+
+func (f *fakeClient) Ping(ctx context.Context) *libredis.StatusCmd {
+	cmd := libredis.NewStatusCmd(ctx)
+	if f.isDown() {
+		cmd.SetErr(errors.New("connection refused"))
+		return cmd
+	}
+	cmd.SetVal("PONG")
+	return cmd
+}
+
+func (f *fakeClient) Get(ctx context.Context, key string) *libredis.StringCmd {
+	return libredis.NewStringCmd(ctx)
+}
+
+func (f *fakeClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *libredis.StatusCmd {
+	return libredis.NewStatusCmd(ctx)
+}
+
+func (f *fakeClient) Watch(ctx context.Context, handler func(*libredis.Tx) error, keys ...string) error {
+	return nil
+}
+
+func (f *fakeClient) Del(ctx context.Context, keys ...string) *libredis.IntCmd {
+	return libredis.NewIntCmd(ctx)
+}
+
+func (f *fakeClient) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *libredis.BoolCmd {
+	return libredis.NewBoolCmd(ctx)
+}
+
+func (f *fakeClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *libredis.Cmd {
+	return f.evalReply(ctx, args)
+}
+
+func (f *fakeClient) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *libredis.Cmd {
+	return f.evalReply(ctx, args)
+}
+
+func (f *fakeClient) ScriptLoad(ctx context.Context, script string) *libredis.StringCmd {
+	return libredis.NewStringCmd(ctx)
+}
+
+// evalReply fakes getValScript's reply: {count, ttlMS}, counting how many times it actually ran
+// against a reachable redis.
+func (f *fakeClient) evalReply(ctx context.Context, args []interface{}) *libredis.Cmd {
+	cmd := libredis.NewCmd(ctx)
+	if f.isDown() {
+		cmd.SetErr(errors.New("connection refused"))
+		return cmd
+	}
+	atomic.AddInt32(&f.evalCalls, 1)
+	cmd.SetVal([]interface{}{int64(1), args[1]})
+	return cmd
+}
+
+func TestFailoverStoreRecoversAfterCooldownUnderContinuousTraffic(t *testing.T) {
+	client := &fakeClient{}
+	cooldown := 50 * time.Millisecond
+
+	store, err := redis.NewFailoverStore(client, limiter.StoreOptions{Prefix: "failover-test"}, redis.FailoverOptions{
+		Policy:           redis.FailOpen,
+		FailureThreshold: 1,
+		CooldownPeriod:   cooldown,
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	rate := limiter.Rate{Limit: 10, Period: time.Minute}
+
+	client.setDown(true)
+
+	// Trip the breaker, then keep hammering it with requests faster than cooldown -- this used
+	// to reset the cooldown timer on every request and keep the breaker open forever.
+	deadline := time.Now().Add(3 * cooldown)
+	for time.Now().Before(deadline) {
+		_, err := store.Get(ctx, "alice", rate)
+		require.NoError(t, err, "FailOpen must not return an error")
+		time.Sleep(cooldown / 10)
+	}
+
+	require.EqualValues(t, 0, atomic.LoadInt32(&client.evalCalls), "expected no successful eval calls while redis is down")
+
+	client.setDown(false)
+
+	// Keep polling at the same cadence; once cooldown has elapsed since the breaker opened, the
+	// next request must probe redis, see it healthy, and resume calling through to it.
+	recovered := false
+	deadline = time.Now().Add(3 * cooldown)
+	for time.Now().Before(deadline) {
+		_, err := store.Get(ctx, "alice", rate)
+		require.NoError(t, err)
+		if atomic.LoadInt32(&client.evalCalls) > 0 {
+			recovered = true
+			break
+		}
+		time.Sleep(cooldown / 10)
+	}
+
+	require.True(t, recovered, "breaker never recovered: redis was reachable but requests kept being served from fallback")
+}
+
+// TestFailoverStoreServesPrimaryWhenRedisHealthyRealRedis complements the fakeClient-based timing
+// test above with a real EVAL/EVALSHA happy path: the breaker must stay closed and every request
+// must go through to redis when it's actually reachable.
+func TestFailoverStoreServesPrimaryWhenRedisHealthyRealRedis(t *testing.T) {
+	client := requireRedisURI(t)
+	ctx := context.Background()
+	require.NoError(t, client.FlushDB(ctx).Err())
+	defer client.FlushDB(ctx)
+
+	store, err := redis.NewFailoverStore(client, limiter.StoreOptions{Prefix: "failover-it"}, redis.FailoverOptions{
+		Policy: redis.FailClosed,
+	})
+	require.NoError(t, err)
+
+	rate := limiter.Rate{Limit: 2, Period: time.Minute}
+
+	for i := 0; i < 2; i++ {
+		lctx, err := store.Get(ctx, "alice", rate)
+		require.NoError(t, err)
+		require.False(t, lctx.Reached, "request %d should have been allowed", i)
+	}
+
+	lctx, err := store.Get(ctx, "alice", rate)
+	require.NoError(t, err)
+	require.True(t, lctx.Reached, "expected Reached=true once the limit is exhausted")
+}
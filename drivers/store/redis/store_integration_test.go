@@ -0,0 +1,28 @@
+package redis_test
+
+import (
+	"os"
+	"testing"
+
+	libredis "github.com/go-redis/redis/v8"
+)
+
+// requireRedisURI skips the test unless REDIS_URI points at a real redis server, consistent with
+// this package's REDIS_URI-gated integration tests: Lua-script-heavy stores need at least one run
+// against a real EVAL/EVALSHA path, since miniredis's Lua emulation is known to diverge from real
+// Redis on script/float/TTL edge cases.
+func requireRedisURI(t *testing.T) *libredis.Client {
+	t.Helper()
+
+	uri := os.Getenv("REDIS_URI")
+	if uri == "" {
+		t.Skip("REDIS_URI not set, skipping integration test against a real redis server")
+	}
+
+	opts, err := libredis.ParseURL(uri)
+	if err != nil {
+		t.Fatalf("cannot parse REDIS_URI %q: %v", uri, err)
+	}
+
+	return libredis.NewClient(opts)
+}
@@ -0,0 +1,207 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ulule/limiter"
+	"github.com/ulule/limiter/drivers/store/common"
+)
+
+// slidingWindowScript maintains a sorted set of request timestamps per key: it purges entries
+// older than the window, reads the current count, and -- if still under the limit -- records the
+// current request and (re)sets the key's expiration. It returns the score of the oldest remaining
+// entry (0 if the set is empty) plus a count, so the caller can derive a precise reset time
+// instead of a fixed window boundary. The returned count is limit+1 when the request was rejected
+// -- rather than the unchanged, always-<=-limit ZCARD value -- so common.GetContextFromState's
+// count<=limit check correctly reports Reached.
+const slidingWindowScript = `
+redis.call("ZREMRANGEBYSCORE", KEYS[1], 0, ARGV[1] - ARGV[2])
+local count = redis.call("ZCARD", KEYS[1])
+local limit = tonumber(ARGV[3])
+if count < limit then
+	redis.call("ZADD", KEYS[1], ARGV[1], ARGV[1] .. ":" .. ARGV[4])
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	count = count + 1
+else
+	count = limit + 1
+end
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+local oldestScore = 0
+if #oldest > 0 then
+	oldestScore = oldest[2]
+end
+return {count, tonumber(oldestScore)}
+`
+
+var slidingWindowScriptSHA = fmt.Sprintf("%x", sha1.Sum([]byte(slidingWindowScript)))
+
+// slidingWindowPeekScript purges expired entries and returns the current count along with the
+// score of the oldest remaining entry, without recording a new request. Like slidingWindowScript,
+// it reports limit+1 once the window is full, so Peek's Reached is consistent with Get's.
+const slidingWindowPeekScript = `
+redis.call("ZREMRANGEBYSCORE", KEYS[1], 0, ARGV[1] - ARGV[2])
+local count = redis.call("ZCARD", KEYS[1])
+local limit = tonumber(ARGV[3])
+if count >= limit then
+	count = limit + 1
+end
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+local oldestScore = 0
+if #oldest > 0 then
+	oldestScore = oldest[2]
+end
+return {count, tonumber(oldestScore)}
+`
+
+var slidingWindowPeekScriptSHA = fmt.Sprintf("%x", sha1.Sum([]byte(slidingWindowPeekScript)))
+
+// SlidingWindowStore is a redis store that enforces a rolling-window limit using a sorted set of
+// request timestamps, rather than a fixed-window counter. This avoids the burst that a fixed
+// window allows around its boundary, at the cost of one sorted-set entry per request within the
+// window.
+type SlidingWindowStore struct {
+	// Prefix used for the key.
+	Prefix string
+	// client used to communicate with redis server.
+	client Client
+}
+
+// NewSlidingWindowStore returns an instance of redis sliding-window store with options.
+func NewSlidingWindowStore(client Client, options limiter.StoreOptions) (limiter.Store, error) {
+	store := &SlidingWindowStore{
+		client: client,
+		Prefix: options.Prefix,
+	}
+
+	_, err := store.ping(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Get returns the limit for given identifier.
+func (store *SlidingWindowStore) Get(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	key = fmt.Sprintf("%s:%s", store.Prefix, key)
+	now := time.Now()
+
+	count, oldest, err := store.evalSlidingWindow(ctx, key, rate.Period, rate.Limit)
+	if err != nil {
+		err = errors.Wrapf(err, "limiter: cannot get value for %s", key)
+		return limiter.Context{}, err
+	}
+
+	expiration := resetFromOldest(now, rate.Period, oldest)
+	return common.GetContextFromState(now, rate, expiration, count), nil
+}
+
+// Peek returns the limit for given identifier, without modification on current values.
+func (store *SlidingWindowStore) Peek(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	key = fmt.Sprintf("%s:%s", store.Prefix, key)
+	now := time.Now()
+
+	count, oldest, err := store.evalSlidingWindowPeek(ctx, key, rate.Period, rate.Limit)
+	if err != nil {
+		err = errors.Wrapf(err, "limiter: cannot peek value for %s", key)
+		return limiter.Context{}, err
+	}
+
+	expiration := resetFromOldest(now, rate.Period, oldest)
+	return common.GetContextFromState(now, rate, expiration, count), nil
+}
+
+// evalSlidingWindow purges entries older than period, and -- if the resulting count is under
+// limit -- records the current request. It returns the count after the purge including the new
+// entry, or limit+1 when the request was rejected, and the timestamp, in milliseconds, of the
+// oldest remaining entry.
+func (store *SlidingWindowStore) evalSlidingWindow(ctx context.Context, key string, period time.Duration, limit int64) (int64, int64, error) {
+	nowMS := time.Now().UnixNano() / int64(time.Millisecond)
+	periodMS := int64(period / time.Millisecond)
+
+	reply, err := store.client.EvalSha(ctx, slidingWindowScriptSHA, []string{key}, nowMS, periodMS, limit, member()).Result()
+	if err != nil && isNoScriptErr(err) {
+		reply, err = store.client.Eval(ctx, slidingWindowScript, []string{key}, nowMS, periodMS, limit, member()).Result()
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return parseCountAndOldest(reply)
+}
+
+// evalSlidingWindowPeek purges entries older than period and returns the resulting count (limit+1
+// once it has reached limit) and the timestamp, in milliseconds, of the oldest remaining entry,
+// without recording a new request.
+func (store *SlidingWindowStore) evalSlidingWindowPeek(ctx context.Context, key string, period time.Duration, limit int64) (int64, int64, error) {
+	nowMS := time.Now().UnixNano() / int64(time.Millisecond)
+	periodMS := int64(period / time.Millisecond)
+
+	reply, err := store.client.EvalSha(ctx, slidingWindowPeekScriptSHA, []string{key}, nowMS, periodMS, limit).Result()
+	if err != nil && isNoScriptErr(err) {
+		reply, err = store.client.Eval(ctx, slidingWindowPeekScript, []string{key}, nowMS, periodMS, limit).Result()
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return parseCountAndOldest(reply)
+}
+
+// parseCountAndOldest decodes the {count, oldestScore} reply shared by the sliding-window scripts.
+func parseCountAndOldest(reply interface{}) (int64, int64, error) {
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return 0, 0, errors.New("limiter: unexpected reply from sliding window script")
+	}
+
+	count, err := toInt64(values[0])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	oldest, err := toInt64(values[1])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return count, oldest, nil
+}
+
+// resetFromOldest computes a precise reset time from the oldest entry still in the window: it
+// expires period after that entry, falling back to now+period when the window is empty.
+func resetFromOldest(now time.Time, period time.Duration, oldestMS int64) time.Time {
+	if oldestMS <= 0 {
+		return now.Add(period)
+	}
+	return time.Unix(0, oldestMS*int64(time.Millisecond)).Add(period)
+}
+
+// member returns a unique sorted-set member suffix so concurrent requests landing on the same
+// millisecond never collide.
+func member() string {
+	buf := make([]byte, 8)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return fmt.Sprintf("%x", buf)
+}
+
+// ping checks if redis is alive.
+func (store *SlidingWindowStore) ping(ctx context.Context) (bool, error) {
+	cmd := store.client.Ping(ctx)
+
+	pong, err := cmd.Result()
+	if err != nil {
+		return false, errors.Wrap(err, "limiter: cannot ping redis server")
+	}
+
+	return (pong == "PONG"), nil
+}
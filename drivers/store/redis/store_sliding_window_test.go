@@ -0,0 +1,87 @@
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	libredis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter"
+	"github.com/ulule/limiter/drivers/store/redis"
+)
+
+func newSlidingWindowTestStore(t *testing.T) (limiter.Store, func()) {
+	t.Helper()
+
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+
+	client := libredis.NewClient(&libredis.Options{Addr: server.Addr()})
+	store, err := redis.NewSlidingWindowStore(client, limiter.StoreOptions{Prefix: "sliding-window-test"})
+	require.NoError(t, err)
+
+	return store, server.Close
+}
+
+func TestSlidingWindowStoreGetReachedOnceFull(t *testing.T) {
+	store, cleanup := newSlidingWindowTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	rate := limiter.Rate{Limit: 3, Period: time.Minute}
+
+	for i := 0; i < 3; i++ {
+		lctx, err := store.Get(ctx, "alice", rate)
+		require.NoError(t, err)
+		require.False(t, lctx.Reached, "request %d should have been allowed", i)
+	}
+
+	lctx, err := store.Get(ctx, "alice", rate)
+	require.NoError(t, err)
+	require.True(t, lctx.Reached, "expected Reached=true once the window is full")
+	require.Equal(t, int64(0), lctx.Remaining)
+}
+
+func TestSlidingWindowStorePeekMatchesGet(t *testing.T) {
+	store, cleanup := newSlidingWindowTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	rate := limiter.Rate{Limit: 1, Period: time.Minute}
+
+	_, err := store.Get(ctx, "bob", rate)
+	require.NoError(t, err)
+
+	lctx, err := store.Peek(ctx, "bob", rate)
+	require.NoError(t, err)
+	require.True(t, lctx.Reached, "expected Peek to report Reached=true once the window is full")
+}
+
+// TestSlidingWindowStoreGetReachedOnceFullRealRedis is the real-redis counterpart to
+// TestSlidingWindowStoreGetReachedOnceFull: miniredis's Lua emulation is known to diverge from
+// real Redis on script/TTL edge cases, so the sorted-set script needs coverage against an actual
+// server too.
+func TestSlidingWindowStoreGetReachedOnceFullRealRedis(t *testing.T) {
+	client := requireRedisURI(t)
+	ctx := context.Background()
+	require.NoError(t, client.FlushDB(ctx).Err())
+	defer client.FlushDB(ctx)
+
+	store, err := redis.NewSlidingWindowStore(client, limiter.StoreOptions{Prefix: "sliding-window-it"})
+	require.NoError(t, err)
+
+	rate := limiter.Rate{Limit: 3, Period: time.Minute}
+
+	for i := 0; i < 3; i++ {
+		lctx, err := store.Get(ctx, "alice", rate)
+		require.NoError(t, err)
+		require.False(t, lctx.Reached, "request %d should have been allowed", i)
+	}
+
+	lctx, err := store.Get(ctx, "alice", rate)
+	require.NoError(t, err)
+	require.True(t, lctx.Reached, "expected Reached=true once the window is full")
+}
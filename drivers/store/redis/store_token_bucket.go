@@ -0,0 +1,256 @@
+package redis
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ulule/limiter"
+)
+
+// tokenBucketScript holds the bucket state (tokens, last_refill_ms) in a hash per key. On each
+// call it refills the bucket based on elapsed time, then -- if enough tokens are available --
+// deducts the requested cost. It returns whether the request was allowed, the tokens remaining,
+// and the number of milliseconds until enough tokens will have refilled to afford cost (0 when
+// allowed).
+const tokenBucketScript = `
+local tokens = tonumber(redis.call("HGET", KEYS[1], "tokens"))
+local lastRefill = tonumber(redis.call("HGET", KEYS[1], "last_refill_ms"))
+local now = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local refillPerMS = tonumber(ARGV[3])
+local cost = tonumber(ARGV[4])
+local ttlMS = tonumber(ARGV[5])
+
+if tokens == nil or lastRefill == nil then
+	tokens = burst
+	lastRefill = now
+end
+
+local elapsed = now - lastRefill
+if elapsed > 0 then
+	tokens = math.min(burst, tokens + elapsed * refillPerMS)
+	lastRefill = now
+end
+
+local allowed = 0
+local retryAfterMS = 0
+if tokens >= cost then
+	tokens = tokens - cost
+	allowed = 1
+else
+	retryAfterMS = math.ceil((cost - tokens) / refillPerMS)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tokens, "last_refill_ms", lastRefill)
+redis.call("PEXPIRE", KEYS[1], ttlMS)
+
+return {allowed, tostring(tokens), retryAfterMS}
+`
+
+var tokenBucketScriptSHA = fmt.Sprintf("%x", sha1.Sum([]byte(tokenBucketScript)))
+
+// tokenBucketPeekScript reports what tokenBucketScript would see -- the bucket's tokens after
+// refilling for elapsed time -- without consuming anything or writing the refill back.
+const tokenBucketPeekScript = `
+local tokens = tonumber(redis.call("HGET", KEYS[1], "tokens"))
+local lastRefill = tonumber(redis.call("HGET", KEYS[1], "last_refill_ms"))
+local now = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local refillPerMS = tonumber(ARGV[3])
+
+if tokens == nil or lastRefill == nil then
+	tokens = burst
+else
+	local elapsed = now - lastRefill
+	if elapsed > 0 then
+		tokens = math.min(burst, tokens + elapsed * refillPerMS)
+	end
+end
+
+return tostring(tokens)
+`
+
+var tokenBucketPeekScriptSHA = fmt.Sprintf("%x", sha1.Sum([]byte(tokenBucketPeekScript)))
+
+// TokenBucketStore is a redis store that smooths rate limiting with a token bucket: requests are
+// allowed as long as tokens are available, tokens refill continuously at the rate.Limit/rate.Period
+// passed to Get/Peek, and Burst -- fixed per store, since limiter.Rate has no room for it -- lets
+// clients temporarily exceed that steady-state rate.
+type TokenBucketStore struct {
+	// Prefix used for the key.
+	Prefix string
+	// Burst is the maximum number of tokens the bucket can hold; tokens refill at the steady-state
+	// Limit/Period carried by the limiter.Rate passed to Get, GetVal and Peek.
+	Burst int64
+	// client used to communicate with redis server.
+	client Client
+}
+
+// NewTokenBucketStore returns an instance of redis token-bucket store with options. burst is the
+// maximum number of tokens the bucket can accumulate.
+func NewTokenBucketStore(client Client, options limiter.StoreOptions, burst int64) (limiter.Store, error) {
+	store := &TokenBucketStore{
+		client: client,
+		Prefix: options.Prefix,
+		Burst:  burst,
+	}
+
+	_, err := store.ping(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Get consumes a single token for given identifier.
+func (store *TokenBucketStore) Get(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	return store.GetVal(ctx, key, rate, 1)
+}
+
+// GetVal consumes cost tokens for given identifier.
+func (store *TokenBucketStore) GetVal(ctx context.Context, key string, rate limiter.Rate, cost int64) (limiter.Context, error) {
+	prefixed := fmt.Sprintf("%s:%s", store.Prefix, key)
+	now := time.Now()
+
+	allowed, tokens, retryAfter, err := store.evalTokenBucket(ctx, prefixed, rate, cost)
+	if err != nil {
+		err = errors.Wrapf(err, "limiter: cannot get value for %s", prefixed)
+		return limiter.Context{}, err
+	}
+
+	reset := now
+	if !allowed {
+		reset = now.Add(retryAfter)
+	}
+
+	return limiter.Context{
+		Limit:     store.Burst,
+		Remaining: int64(tokens),
+		Reset:     reset.Unix(),
+		Reached:   !allowed,
+	}, nil
+}
+
+// Peek returns the limit for given identifier, without consuming a token.
+func (store *TokenBucketStore) Peek(ctx context.Context, key string, rate limiter.Rate) (limiter.Context, error) {
+	prefixed := fmt.Sprintf("%s:%s", store.Prefix, key)
+	now := time.Now()
+
+	tokens, err := store.evalTokenBucketPeek(ctx, prefixed, rate)
+	if err != nil {
+		err = errors.Wrapf(err, "limiter: cannot peek value for %s", prefixed)
+		return limiter.Context{}, err
+	}
+
+	return limiter.Context{
+		Limit:     store.Burst,
+		Remaining: int64(tokens),
+		Reset:     now.Unix(),
+		Reached:   tokens < 1,
+	}, nil
+}
+
+// ttlMS is how long the bucket's hash should live before it's safe to expire: long enough for a
+// drained bucket to refill all the way to Burst, not just rate.Period. Tying the TTL to rate.Period
+// alone would let the key expire -- and the next request reset straight to a full Burst -- after
+// an idle gap shorter than a full refill whenever Burst refills slower than once per Period.
+func (store *TokenBucketStore) ttlMS(rate limiter.Rate, refillPerMS float64) int64 {
+	periodMS := int64(rate.Period / time.Millisecond)
+	fillTimeMS := int64(math.Ceil(float64(store.Burst) / refillPerMS))
+	if fillTimeMS > periodMS {
+		return fillTimeMS
+	}
+	return periodMS
+}
+
+// evalTokenBucket runs tokenBucketScript for key, returning whether the request was allowed, the
+// number of tokens left in the bucket, and -- when not allowed -- how long to wait before the
+// bucket will hold cost tokens again.
+func (store *TokenBucketStore) evalTokenBucket(ctx context.Context, key string, rate limiter.Rate, cost int64) (bool, float64, time.Duration, error) {
+	nowMS := time.Now().UnixNano() / int64(time.Millisecond)
+	refillPerMS := float64(rate.Limit) / float64(rate.Period/time.Millisecond)
+
+	args := []interface{}{nowMS, store.Burst, refillPerMS, cost, store.ttlMS(rate, refillPerMS)}
+
+	reply, err := store.client.EvalSha(ctx, tokenBucketScriptSHA, []string{key}, args...).Result()
+	if err != nil && isNoScriptErr(err) {
+		reply, err = store.client.Eval(ctx, tokenBucketScript, []string{key}, args...).Result()
+	}
+	if err != nil {
+		return false, 0, 0, err
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 3 {
+		return false, 0, 0, errors.New("limiter: unexpected reply from tokenBucketScript")
+	}
+
+	allowed, err := toInt64(values[0])
+	if err != nil {
+		return false, 0, 0, err
+	}
+
+	tokensLeft, err := parseTokens(values[1])
+	if err != nil {
+		return false, 0, 0, err
+	}
+
+	retryAfterMS, err := toInt64(values[2])
+	if err != nil {
+		return false, 0, 0, err
+	}
+
+	return allowed == 1, tokensLeft, time.Duration(retryAfterMS) * time.Millisecond, nil
+}
+
+// evalTokenBucketPeek runs tokenBucketPeekScript for key, returning the tokens the bucket would
+// hold right now, without consuming or writing anything back.
+func (store *TokenBucketStore) evalTokenBucketPeek(ctx context.Context, key string, rate limiter.Rate) (float64, error) {
+	nowMS := time.Now().UnixNano() / int64(time.Millisecond)
+	refillPerMS := float64(rate.Limit) / float64(rate.Period/time.Millisecond)
+
+	args := []interface{}{nowMS, store.Burst, refillPerMS}
+
+	reply, err := store.client.EvalSha(ctx, tokenBucketPeekScriptSHA, []string{key}, args...).Result()
+	if err != nil && isNoScriptErr(err) {
+		reply, err = store.client.Eval(ctx, tokenBucketPeekScript, []string{key}, args...).Result()
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return parseTokens(reply)
+}
+
+// parseTokens decodes the stringified token count returned by the token-bucket scripts.
+func parseTokens(reply interface{}) (float64, error) {
+	tokens, ok := reply.(string)
+	if !ok {
+		return 0, errors.New("limiter: unexpected tokens reply from token bucket script")
+	}
+
+	var tokensLeft float64
+	if _, err := fmt.Sscanf(tokens, "%g", &tokensLeft); err != nil {
+		return 0, errors.Wrap(err, "limiter: cannot parse tokens reply")
+	}
+
+	return tokensLeft, nil
+}
+
+// ping checks if redis is alive.
+func (store *TokenBucketStore) ping(ctx context.Context) (bool, error) {
+	cmd := store.client.Ping(ctx)
+
+	pong, err := cmd.Result()
+	if err != nil {
+		return false, errors.Wrap(err, "limiter: cannot ping redis server")
+	}
+
+	return (pong == "PONG"), nil
+}
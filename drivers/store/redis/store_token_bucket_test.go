@@ -0,0 +1,165 @@
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	libredis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ulule/limiter"
+	"github.com/ulule/limiter/drivers/store/redis"
+)
+
+func newTokenBucketTestStore(t *testing.T, burst int64) (limiter.Store, func()) {
+	t.Helper()
+
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("cannot start miniredis: %v", err)
+	}
+
+	client := libredis.NewClient(&libredis.Options{Addr: server.Addr()})
+	store, err := redis.NewTokenBucketStore(client, limiter.StoreOptions{Prefix: "token-bucket-test"}, burst)
+	if err != nil {
+		t.Fatalf("cannot create token bucket store: %v", err)
+	}
+
+	return store, server.Close
+}
+
+// Compile-time check that TokenBucketStore implements
+// limiter.Store, so it can be handed to limiter.New and any existing middleware.
+var _ limiter.Store = (*redis.TokenBucketStore)(nil)
+
+func TestTokenBucketStoreAllowsUpToBurstThenBlocks(t *testing.T) {
+	store, cleanup := newTokenBucketTestStore(t, 2)
+	defer cleanup()
+
+	ctx := context.Background()
+	rate := limiter.Rate{Limit: 1, Period: time.Minute}
+
+	for i := 0; i < 2; i++ {
+		lctx, err := store.Get(ctx, "alice", rate)
+		if err != nil {
+			t.Fatalf("unexpected error on request %d: %v", i, err)
+		}
+		if lctx.Reached {
+			t.Fatalf("request %d should have been allowed by the burst, got Reached=true", i)
+		}
+	}
+
+	lctx, err := store.Get(ctx, "alice", rate)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !lctx.Reached {
+		t.Fatalf("expected Reached=true once burst is exhausted")
+	}
+}
+
+func TestTokenBucketStorePeekDoesNotConsume(t *testing.T) {
+	store, cleanup := newTokenBucketTestStore(t, 1)
+	defer cleanup()
+
+	ctx := context.Background()
+	rate := limiter.Rate{Limit: 1, Period: time.Minute}
+
+	before, err := store.Peek(ctx, "bob", rate)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if before.Reached {
+		t.Fatalf("expected a fresh bucket to be full, got Reached=true")
+	}
+
+	after, err := store.Peek(ctx, "bob", rate)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if after.Remaining != before.Remaining {
+		t.Fatalf("Peek must not consume tokens, got Remaining %d then %d", before.Remaining, after.Remaining)
+	}
+}
+
+// TestTokenBucketStoreKeySurvivesUntilBurstWouldRefill reproduces the bug where the bucket's TTL
+// was tied to rate.Period alone: once Burst takes longer than Period to refill from empty, the key
+// expired after just over a Period of idleness and the next request saw a reset straight to a full
+// Burst instead of the fraction of a token that should have refilled.
+func TestTokenBucketStoreKeySurvivesUntilBurstWouldRefill(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	client := libredis.NewClient(&libredis.Options{Addr: server.Addr()})
+	store, err := redis.NewTokenBucketStore(client, limiter.StoreOptions{Prefix: "token-bucket-ttl-test"}, 100)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	rate := limiter.Rate{Limit: 1, Period: time.Second}
+
+	for i := 0; i < 100; i++ {
+		_, err := store.Get(ctx, "alice", rate)
+		require.NoError(t, err)
+	}
+
+	// Just past rate.Period -- long enough to have expired the key under the old, Period-only TTL,
+	// but far short of the ~100s a full Burst refill needs. FastForward only advances miniredis's
+	// TTLs; the script's clock is time.Now(), so almost no tokens refill here.
+	server.FastForward(1100 * time.Millisecond)
+
+	lctx, err := store.Get(ctx, "alice", rate)
+	require.NoError(t, err)
+	require.True(t, lctx.Reached, "the bucket should still be nearly empty, not reset to a full burst")
+}
+
+func TestTokenBucketStoreAllowsUpToBurstThenBlocksRealRedis(t *testing.T) {
+	client := requireRedisURI(t)
+	ctx := context.Background()
+	require.NoError(t, client.FlushDB(ctx).Err())
+	defer client.FlushDB(ctx)
+
+	store, err := redis.NewTokenBucketStore(client, limiter.StoreOptions{Prefix: "token-bucket-it"}, 2)
+	require.NoError(t, err)
+
+	rate := limiter.Rate{Limit: 1, Period: time.Minute}
+
+	for i := 0; i < 2; i++ {
+		lctx, err := store.Get(ctx, "alice", rate)
+		require.NoError(t, err)
+		require.False(t, lctx.Reached, "request %d should have been allowed by the burst", i)
+	}
+
+	lctx, err := store.Get(ctx, "alice", rate)
+	require.NoError(t, err)
+	require.True(t, lctx.Reached)
+}
+
+// TestTokenBucketStoreRetainsPartialRefillAcrossIdlePeriodRealRedis is the real-redis counterpart
+// to TestTokenBucketStoreKeySurvivesUntilBurstWouldRefill: miniredis's Lua emulation is known to
+// diverge from real Redis on exactly this kind of TTL/float edge case, so the fix also needs
+// coverage against an actual server.
+func TestTokenBucketStoreRetainsPartialRefillAcrossIdlePeriodRealRedis(t *testing.T) {
+	client := requireRedisURI(t)
+	ctx := context.Background()
+	require.NoError(t, client.FlushDB(ctx).Err())
+	defer client.FlushDB(ctx)
+
+	store, err := redis.NewTokenBucketStore(client, limiter.StoreOptions{Prefix: "token-bucket-ttl-it"}, 100)
+	require.NoError(t, err)
+
+	rate := limiter.Rate{Limit: 1, Period: time.Second}
+
+	for i := 0; i < 100; i++ {
+		_, err := store.Get(ctx, "alice", rate)
+		require.NoError(t, err)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+
+	lctx, err := store.Get(ctx, "alice", rate)
+	require.NoError(t, err)
+	require.True(t, lctx.Reached, "only ~1 token should have refilled, not a full burst")
+}